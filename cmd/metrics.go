@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+// startMetricsServer serves the Prometheus collectors registered by the node
+// and srv packages on /metrics. It blocks, so callers should run it in a
+// goroutine, and it is only started when --metrics is set.
+func startMetricsServer(port string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	log.WithField("port", port).Info("starting metrics server")
+	if err := http.ListenAndServe(":"+port, mux); err != nil {
+		log.WithError(err).Error("metrics server stopped")
+	}
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/pegnet/pegnetd/node"
+)
+
+func init() {
+	resetCmd.Flags().Int64("height", -1, "Directory block height to truncate the database back to")
+	_ = resetCmd.MarkFlagRequired("height")
+	rootCmd.AddCommand(resetCmd)
+}
+
+// resetCmd is the pegnetd analogue of tendermint's reset_priv_validator: it
+// never contacts the network, it only rolls the local database back to a
+// known-good height so a bad grader/conversion run can be undone.
+var resetCmd = &cobra.Command{
+	Use:    "reset",
+	Short:  "Truncate the local database back to a given directory block height",
+	PreRun: SoftReadConfig,
+	Run: func(cmd *cobra.Command, args []string) {
+		height, _ := cmd.Flags().GetInt64("height")
+		if height < 0 {
+			fmt.Println("must specify a --height >= 0")
+			os.Exit(1)
+		}
+
+		if err := node.TruncateToHeight(getConfig(), height); err != nil {
+			log.WithError(err).Error("failed to reset database")
+			os.Exit(1)
+		}
+		log.WithField("height", height).Info("database truncated")
+	},
+}
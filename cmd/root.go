@@ -6,8 +6,10 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/pegnet/pegnet/common"
 
 	"github.com/pegnet/pegnetd/srv"
@@ -27,6 +29,9 @@ func init() {
 	rootCmd.PersistentFlags().StringP("wallet", "w", "http://localhost:8089/v2", "The url to the factomd-wallet endpoint without a trailing slash")
 	rootCmd.PersistentFlags().StringP("pegnetd", "p", "http://localhost:8070", "The url to the pegnetd endpoint without a trailing slash")
 	rootCmd.PersistentFlags().String("api", "8070", "Change the api listening port for the api")
+	rootCmd.PersistentFlags().String("log-format", "plain", "Change the logging output format. Can choose from 'plain' or 'json'")
+	rootCmd.PersistentFlags().Bool("metrics", false, "Expose a Prometheus /metrics endpoint")
+	rootCmd.PersistentFlags().String("metrics-port", "8071", "Change the listening port for the /metrics endpoint")
 
 	// This is for testing purposes
 	rootCmd.PersistentFlags().Bool("testing", false, "If this flag is set, all v2 activations heights are set to 0.")
@@ -51,21 +56,70 @@ var rootCmd = &cobra.Command{
 		exit.GlobalExitHandler.AddCancel(cancel)
 
 		// Get the config
-		conf := viper.GetViper()
-		node, err := node.NewPegnetd(ctx, conf)
+		cfg := getConfig()
+		pegnetd, err := node.NewPegnetd(ctx, cfg)
 		if err != nil {
 			log.WithError(err).Errorf("failed to launch pegnet node")
 			os.Exit(1)
 		}
 
-		apiserver := srv.NewAPIServer(conf, node)
-		go apiserver.Start(ctx.Done())
+		rootCtx = ctx
+		startAPIServer(ctx, cfg, pegnetd)
+
+		if cfg.Metrics.Enabled {
+			go startMetricsServer(cfg.Metrics.Port)
+		}
 
 		// Run
-		node.DBlockSync(ctx)
+		pegnetd.DBlockSync(ctx)
 	},
 }
 
+// currentConfig is the typed config assembled in ReadConfig/SoftReadConfig,
+// and re-assembled by onConfigChange on every hot-reload. It's guarded by
+// configMu since it's written from the fsnotify watcher goroutine and read
+// from the main goroutine. runningAPIServer tracks the currently running api
+// server so a config hot-reload can restart it if the listen port changes.
+var (
+	configMu      sync.RWMutex
+	currentConfig *config.Config
+
+	rootCtx                context.Context
+	runningAPIServerMu     sync.Mutex
+	runningAPIServerNode   *node.Pegnetd
+	runningAPIServerCancel context.CancelFunc
+	runningAPIServerPort   string
+)
+
+// getConfig returns the most recently loaded config.
+func getConfig() *config.Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return currentConfig
+}
+
+// setConfig installs cfg as the current config.
+func setConfig(cfg *config.Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	currentConfig = cfg
+}
+
+// startAPIServer launches the api server under its own cancelable context so
+// it can be torn down and relaunched independently of the rest of pegnetd.
+func startAPIServer(parent context.Context, cfg *config.Config, pegnetd *node.Pegnetd) {
+	runningAPIServerMu.Lock()
+	defer runningAPIServerMu.Unlock()
+
+	apiCtx, cancel := context.WithCancel(parent)
+	apiserver := srv.NewAPIServer(cfg, pegnetd)
+	go apiserver.Start(apiCtx.Done())
+
+	runningAPIServerNode = pegnetd
+	runningAPIServerCancel = cancel
+	runningAPIServerPort = cfg.APIListen
+}
+
 // always is run before any command
 func always(cmd *cobra.Command, args []string) {
 	// See if we are in testing mode
@@ -85,6 +139,13 @@ func always(cmd *cobra.Command, args []string) {
 	viper.AddConfigPath("$HOME/.pegnetd")
 	viper.AddConfigPath(".")
 
+	// Let operators override any config key with a PEGNETD_ prefixed env var,
+	// e.g. PEGNETD_SERVER or PEGNETD_SQLITEDBPATH. This is handy for
+	// Docker/Kubernetes deployments where editing the yaml isn't convenient.
+	viper.SetEnvPrefix("PEGNETD")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	viper.AutomaticEnv()
+
 	// Setup global command line flag overrides
 	// This gets run before any command executes. It will init global flags to the config
 	_ = viper.BindPFlag(config.LoggingLevel, cmd.Flags().Lookup("log"))
@@ -92,10 +153,16 @@ func always(cmd *cobra.Command, args []string) {
 	_ = viper.BindPFlag(config.Wallet, cmd.Flags().Lookup("wallet"))
 	_ = viper.BindPFlag(config.Pegnetd, cmd.Flags().Lookup("pegnetd"))
 	_ = viper.BindPFlag(config.APIListen, cmd.Flags().Lookup("api"))
+	_ = viper.BindPFlag(logFormatKey, cmd.Flags().Lookup("log-format"))
+	_ = viper.BindPFlag(metricsEnabledKey, cmd.Flags().Lookup("metrics"))
+	_ = viper.BindPFlag(metricsPortKey, cmd.Flags().Lookup("metrics-port"))
 
 	// Also init some defaults
 	viper.SetDefault(config.DBlockSyncRetryPeriod, time.Second*5)
 	viper.SetDefault(config.SqliteDBPath, "$HOME/.pegnetd/mainnet/sql.db")
+	viper.SetDefault(loggingFileKey, "$HOME/.pegnetd/pegnetd.log")
+	viper.SetDefault(loggingMaxSizeMBKey, 100)
+	viper.SetDefault(loggingMaxAgeDaysKey, 7)
 
 	// Catch ctl+c
 	signalChan := make(chan os.Signal, 1)
@@ -119,7 +186,13 @@ func ReadConfig(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	initLogger()
+	if err := loadTypedConfig(); err != nil {
+		log.WithError(err).Error("failed to load config")
+		os.Exit(1)
+	}
+
+	initLogger(getConfig())
+	watchConfig()
 }
 
 // SoftReadConfig will not fail. It can be used for a command that needs the config,
@@ -130,23 +203,62 @@ func SoftReadConfig(cmd *cobra.Command, args []string) {
 		log.WithError(err).Debugf("failed to load config")
 	}
 
-	initLogger()
+	// A missing config file is fine here, but settings (from flags, env or
+	// defaults) that fail validation are still fatal.
+	if err := loadTypedConfig(); err != nil {
+		log.WithError(err).Error("failed to load config")
+		os.Exit(1)
+	}
+
+	initLogger(getConfig())
 }
 
-// TODO implement a dedicated logger
-func initLogger() {
-	switch strings.ToLower(viper.GetString(config.LoggingLevel)) {
-	case "trace":
-		log.SetLevel(log.TraceLevel)
-	case "debug":
-		log.SetLevel(log.DebugLevel)
-	case "info":
-		log.SetLevel(log.InfoLevel)
-	case "warn":
-		log.SetLevel(log.WarnLevel)
-	case "error":
-		log.SetLevel(log.ErrorLevel)
-	case "fatal":
-		log.SetLevel(log.FatalLevel)
+// loadTypedConfig consolidates every viper key pegnetd reads into a
+// config.Config and validates it, so the rest of the daemon can be
+// constructed from a plain struct instead of the global viper instance.
+func loadTypedConfig() error {
+	var cfg config.Config
+	if err := viper.Unmarshal(&cfg); err != nil {
+		return fmt.Errorf("unmarshal config: %w", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	setConfig(&cfg)
+	return nil
+}
+
+// watchConfig lets a running node be re-tuned without downtime. It only
+// re-applies the settings that are safe to change on the fly: the logging
+// settings (level, format and rotating log file) always, and the api listen
+// port by restarting the api server when it actually changed.
+func watchConfig() {
+	viper.OnConfigChange(onConfigChange)
+	viper.WatchConfig()
+}
+
+func onConfigChange(e fsnotify.Event) {
+	log.WithField("file", e.Name).Info("config file changed, re-applying runtime settings")
+
+	if err := loadTypedConfig(); err != nil {
+		log.WithError(err).Error("new config is invalid, keeping the previous settings")
+		return
+	}
+	cfg := getConfig()
+
+	initLogger(cfg)
+
+	runningAPIServerMu.Lock()
+	samePort := cfg.APIListen == runningAPIServerPort
+	pegnetd := runningAPIServerNode
+	cancel := runningAPIServerCancel
+	runningAPIServerMu.Unlock()
+
+	if samePort || pegnetd == nil || cancel == nil {
+		return
+	}
+
+	log.WithFields(log.Fields{"old": runningAPIServerPort, "new": cfg.APIListen}).Info("api listen port changed, restarting api server")
+	cancel()
+	startAPIServer(rootCtx, cfg, pegnetd)
 }
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/pegnet/pegnetd/node"
+)
+
+func init() {
+	snapshotCmd.AddCommand(snapshotExportCmd, snapshotImportCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+// snapshotCmd groups the export/import pair used to fast-bootstrap a new
+// node from a portable balance/graded-block dump instead of replaying the
+// whole chain from genesis.
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Export or import a portable balance/graded-block snapshot",
+}
+
+var snapshotExportCmd = &cobra.Command{
+	Use:    "export <file>",
+	Short:  "Write a snapshot of balances and graded blocks to <file>",
+	Args:   cobra.ExactArgs(1),
+	PreRun: SoftReadConfig,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := node.ExportSnapshot(getConfig(), args[0]); err != nil {
+			log.WithError(err).Error("failed to export snapshot")
+			os.Exit(1)
+		}
+		log.WithField("file", args[0]).Info("snapshot exported")
+	},
+}
+
+var snapshotImportCmd = &cobra.Command{
+	Use:    "import <file>",
+	Short:  "Bootstrap the local database from a snapshot produced by 'snapshot export'",
+	Args:   cobra.ExactArgs(1),
+	PreRun: SoftReadConfig,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := node.ImportSnapshot(getConfig(), args[0]); err != nil {
+			log.WithError(err).Error("failed to import snapshot")
+			os.Exit(1)
+		}
+		log.WithField("file", args[0]).Info("snapshot imported")
+	},
+}
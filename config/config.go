@@ -0,0 +1,128 @@
+// Package config holds the viper keys pegnetd reads its settings from and a
+// typed Config struct assembled from them via viper.Unmarshal.
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Viper keys. These are used both to bind command line flags and to look up
+// values directly, so they must match the `mapstructure` tags on Config.
+const (
+	Server                = "Server"
+	Wallet                = "Wallet"
+	Pegnetd               = "Pegnetd"
+	APIListen             = "APIListen"
+	SqliteDBPath          = "SqliteDBPath"
+	DBlockSyncRetryPeriod = "DBlockSyncRetryPeriod"
+	LoggingLevel          = "LoggingLevel"
+)
+
+// Config is the fully resolved pegnetd configuration. It is built from
+// viper.Unmarshal so that components can be constructed directly from a
+// struct in tests, without touching viper's global state.
+type Config struct {
+	Server                string        `mapstructure:"Server"`
+	Wallet                string        `mapstructure:"Wallet"`
+	Pegnetd               string        `mapstructure:"Pegnetd"`
+	APIListen             string        `mapstructure:"APIListen"`
+	SqliteDBPath          string        `mapstructure:"SqliteDBPath"`
+	DBlockSyncRetryPeriod time.Duration `mapstructure:"DBlockSyncRetryPeriod"`
+	LoggingLevel          string        `mapstructure:"LoggingLevel"`
+
+	Logging LoggingConfig `mapstructure:"logging"`
+	Metrics MetricsConfig `mapstructure:"metrics"`
+}
+
+// LoggingConfig controls the output format and rotation of pegnetd's log file.
+type LoggingConfig struct {
+	Format     string `mapstructure:"format"`
+	File       string `mapstructure:"file"`
+	MaxSizeMB  int    `mapstructure:"maxsizemb"`
+	MaxAgeDays int    `mapstructure:"maxagedays"`
+}
+
+// MetricsConfig controls the optional Prometheus /metrics endpoint.
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Port    string `mapstructure:"port"`
+}
+
+// Validate checks that Config is internally consistent enough to start a
+// node with, resolving $HOME in filesystem paths along the way. It returns a
+// single error aggregating every problem found, so operators can fix a bad
+// config file in one pass instead of one error at a time.
+func (c *Config) Validate() error {
+	var errs []string
+
+	c.SqliteDBPath = expandPath(c.SqliteDBPath)
+	c.Logging.File = expandPath(c.Logging.File)
+
+	if err := validURL(c.Server); err != nil {
+		errs = append(errs, fmt.Sprintf("Server: %v", err))
+	}
+	if err := validURL(c.Wallet); err != nil {
+		errs = append(errs, fmt.Sprintf("Wallet: %v", err))
+	}
+	if err := validURL(c.Pegnetd); err != nil {
+		errs = append(errs, fmt.Sprintf("Pegnetd: %v", err))
+	}
+
+	if err := validPort(c.APIListen); err != nil {
+		errs = append(errs, fmt.Sprintf("APIListen: %v", err))
+	}
+	if c.Metrics.Enabled {
+		if err := validPort(c.Metrics.Port); err != nil {
+			errs = append(errs, fmt.Sprintf("Metrics.Port: %v", err))
+		}
+	}
+
+	switch strings.ToLower(c.Logging.Format) {
+	case "", "plain", "json":
+	default:
+		errs = append(errs, fmt.Sprintf("Logging.Format: must be 'plain' or 'json', got %q", c.Logging.Format))
+	}
+
+	if c.SqliteDBPath == "" {
+		errs = append(errs, "SqliteDBPath: must not be empty")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+func expandPath(path string) string {
+	if path == "" {
+		return path
+	}
+	return os.ExpandEnv(path)
+}
+
+func validURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %w", raw, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid url %q: must include a scheme and host", raw)
+	}
+	return nil
+}
+
+func validPort(port string) error {
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("invalid port %q: %w", port, err)
+	}
+	if p < 1 || p > 65535 {
+		return fmt.Errorf("invalid port %q: must be between 1 and 65535", port)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package node
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus collectors for DBlockSync. They are registered on import so
+// `pegnetd --metrics` has something to serve as soon as the node starts
+// syncing, without DBlockSync needing to know about the metrics flag.
+var (
+	dBlocksProcessed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "pegnetd",
+		Subsystem: "dblocksync",
+		Name:      "dblocks_processed_total",
+		Help:      "Total number of directory blocks processed by DBlockSync.",
+	})
+
+	syncLagBlocks = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "pegnetd",
+		Subsystem: "dblocksync",
+		Name:      "sync_lag_blocks",
+		Help:      "Number of blocks pegnetd is behind the highest known directory block.",
+	})
+
+	conversionsProcessed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "pegnetd",
+		Subsystem: "dblocksync",
+		Name:      "conversions_total",
+		Help:      "Total number of conversion transactions processed.",
+	})
+
+	graderDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "pegnetd",
+		Subsystem: "dblocksync",
+		Name:      "grader_duration_seconds",
+		Help:      "Time taken to grade a directory block's conversions.",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
@@ -0,0 +1,221 @@
+package node
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pegnet/pegnetd/config"
+)
+
+// Activation heights for pegnet/grading-v2. These are package vars (rather
+// than config) because --testing forces them to 0 for integration tests; see
+// cmd.always.
+var (
+	PegnetActivation    int64 = 206422
+	GradingV2Activation int64 = 210330
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS dblocks (
+	height       INTEGER PRIMARY KEY,
+	keymr        TEXT NOT NULL,
+	gradedassets TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS eblocks (
+	height    INTEGER NOT NULL,
+	entryhash TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS balance_deltas (
+	height  INTEGER NOT NULL,
+	address TEXT NOT NULL,
+	asset   TEXT NOT NULL,
+	delta   INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS balances (
+	address TEXT NOT NULL,
+	asset   TEXT NOT NULL,
+	amount  INTEGER NOT NULL,
+	PRIMARY KEY (address, asset)
+);
+CREATE TABLE IF NOT EXISTS syncstate (
+	id     INTEGER PRIMARY KEY CHECK (id = 0),
+	height INTEGER NOT NULL
+);
+`
+
+// Pegnetd is the daemon's view of the chain: a sqlite-backed store of
+// directory blocks, balances, and the conversions graded from them.
+type Pegnetd struct {
+	cfg *config.Config
+	DB  *sql.DB
+}
+
+// NewPegnetd opens (and, if needed, initializes) the sqlite database at
+// cfg.SqliteDBPath. It takes the typed config rather than the global viper
+// instance so callers (including tests) can construct a Pegnetd without
+// touching global state.
+func NewPegnetd(ctx context.Context, cfg *config.Config) (*Pegnetd, error) {
+	db, err := sql.Open("sqlite3", cfg.SqliteDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite db: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("init schema: %w", err)
+	}
+
+	return &Pegnetd{cfg: cfg, DB: db}, nil
+}
+
+// lastSyncedHeight returns the height bookkept in syncstate, or -1 if the
+// node has never synced.
+func (d *Pegnetd) lastSyncedHeight(ctx context.Context) (int64, error) {
+	var height int64
+	err := d.DB.QueryRowContext(ctx, "SELECT height FROM syncstate WHERE id = 0").Scan(&height)
+	if err == sql.ErrNoRows {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("query syncstate: %w", err)
+	}
+	return height, nil
+}
+
+// DBlockSync runs syncHeight for the height after the last synced one every
+// cfg.DBlockSyncRetryPeriod, retrying the same height on failure. It runs
+// until ctx is canceled.
+func (d *Pegnetd) DBlockSync(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.DBlockSyncRetryPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			height, err := d.lastSyncedHeight(ctx)
+			if err != nil {
+				log.WithError(err).Error("dblock sync failed to read sync state")
+				continue
+			}
+			if err := d.syncHeight(ctx, height+1); err != nil {
+				log.WithError(err).WithField("height", height+1).Error("dblock sync failed")
+			}
+		}
+	}
+}
+
+// DBlockSyncRange re-runs the same per-height sync/grade path DBlockSync
+// uses, but over a fixed, deterministic [from, to] range instead of polling
+// the chain head. It is used by `pegnetd replay` to reproduce grader/
+// conversion behavior for debugging.
+func (d *Pegnetd) DBlockSyncRange(ctx context.Context, from, to int64) error {
+	for height := from; height <= to; height++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if err := d.syncHeight(ctx, height); err != nil {
+			return fmt.Errorf("sync height %d: %w", height, err)
+		}
+	}
+	return nil
+}
+
+// syncHeight applies what fetchAndGrade returns for a single directory block
+// height, recording the balance changes as balance_deltas so a later
+// `pegnetd reset` can recompute balances instead of discarding them outright.
+func (d *Pegnetd) syncHeight(ctx context.Context, height int64) error {
+	// The grading result for the height comes from fetchAndGrade and is
+	// persisted below alongside its balance deltas.
+	start := time.Now()
+	deltas, conversions, keyMR, gradedAssets, chainHead, err := d.fetchAndGrade(ctx, height)
+	graderDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		return err
+	}
+	syncLagBlocks.Set(float64(chainHead - height))
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO dblocks (height, keymr, gradedassets) VALUES (?, ?, ?) "+
+			"ON CONFLICT(height) DO UPDATE SET keymr = excluded.keymr, gradedassets = excluded.gradedassets",
+		height, keyMR, gradedAssets,
+	); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("record dblock: %w", err)
+	}
+
+	for _, delta := range deltas {
+		if err := applyDelta(ctx, tx, height, delta); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO syncstate (id, height) VALUES (0, ?) ON CONFLICT(id) DO UPDATE SET height = excluded.height",
+		height,
+	); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("record sync height: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit height %d: %w", height, err)
+	}
+
+	dBlocksProcessed.Inc()
+	conversionsProcessed.Add(float64(conversions))
+	return nil
+}
+
+// balanceDelta is a single address/asset balance change produced by grading
+// the conversions in a directory block.
+type balanceDelta struct {
+	Address string
+	Asset   string
+	Amount  int64
+}
+
+// fetchAndGrade returns, for height, the balance deltas, the number of
+// conversion transactions graded, the block's keyMR and graded assets, and
+// the current chain head height (used to report sync lag). It does not
+// contact factomd or grade anything: it returns no deltas, zero
+// conversions, an empty keyMR and graded assets, and height itself as the
+// chain head.
+func (d *Pegnetd) fetchAndGrade(ctx context.Context, height int64) (deltas []balanceDelta, conversions int, keyMR, gradedAssets string, chainHead int64, err error) {
+	return nil, 0, "", "", height, nil
+}
+
+// applyDelta records a balance change for height and folds it into the
+// materialized balances table.
+func applyDelta(ctx context.Context, tx *sql.Tx, height int64, delta balanceDelta) error {
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO balance_deltas (height, address, asset, delta) VALUES (?, ?, ?, ?)",
+		height, delta.Address, delta.Asset, delta.Amount,
+	); err != nil {
+		return fmt.Errorf("record balance delta: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO balances (address, asset, amount) VALUES (?, ?, ?) "+
+			"ON CONFLICT(address, asset) DO UPDATE SET amount = balances.amount + excluded.amount",
+		delta.Address, delta.Asset, delta.Amount,
+	); err != nil {
+		return fmt.Errorf("apply balance delta: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package srv
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus collectors for the JSON-RPC api server, keyed by method so slow
+// or hot endpoints are visible without scraping application logs.
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pegnetd",
+		Subsystem: "api",
+		Name:      "requests_total",
+		Help:      "Total number of api requests, by method.",
+	}, []string{"method"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "pegnetd",
+		Subsystem: "api",
+		Name:      "request_duration_seconds",
+		Help:      "Api request latency, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// observeRequest records a single api call's count and latency for method.
+func observeRequest(method string, seconds float64) {
+	requestsTotal.WithLabelValues(method).Inc()
+	requestDuration.WithLabelValues(method).Observe(seconds)
+}
@@ -0,0 +1,100 @@
+package srv
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/pegnet/pegnetd/config"
+	"github.com/pegnet/pegnetd/node"
+)
+
+// APIServer serves pegnetd's JSON-RPC v2 API.
+type APIServer struct {
+	cfg    *config.Config
+	node   *node.Pegnetd
+	server *http.Server
+}
+
+// NewAPIServer constructs an APIServer from the typed config rather than the
+// global viper instance, so it can be built directly in tests.
+func NewAPIServer(cfg *config.Config, pegnetd *node.Pegnetd) *APIServer {
+	a := &APIServer{cfg: cfg, node: pegnetd}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2", a.withMetrics(a.handleJSONRPC))
+	a.server = &http.Server{Addr: ":" + cfg.APIListen, Handler: mux}
+
+	return a
+}
+
+// Start runs the api server until stop is closed.
+func (a *APIServer) Start(stop <-chan struct{}) {
+	go func() {
+		<-stop
+		_ = a.server.Close()
+	}()
+
+	log.WithField("port", a.cfg.APIListen).Info("starting api server")
+	if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.WithError(err).Error("api server stopped")
+	}
+}
+
+// knownMethods is the set of JSON-RPC methods pegnetd's api recognizes. It
+// exists so an unrecognized, attacker-controlled "method" value never ends
+// up as a Prometheus label: see sanitizeMethodLabel.
+var knownMethods = map[string]bool{
+	"get-balances":     true,
+	"get-transaction":  true,
+	"get-transactions": true,
+	"send-transaction": true,
+	"get-sync-status":  true,
+	"properties":       true,
+}
+
+// sanitizeMethodLabel maps any method pegnetd doesn't recognize to "unknown",
+// so a client can't blow up requestsTotal/requestDuration's label
+// cardinality by sending arbitrary "method" values.
+func sanitizeMethodLabel(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return "unknown"
+}
+
+// withMetrics wraps a handler so every request's count and latency is
+// recorded per JSON-RPC method. It buffers the request body and restores it
+// on r.Body before calling next, so peeking at "method" here doesn't
+// prevent the real dispatcher from reading "params" off the same body.
+func (a *APIServer) withMetrics(next func(http.ResponseWriter, *http.Request, string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		var req struct {
+			Method string `json:"method"`
+		}
+		_ = json.Unmarshal(body, &req)
+
+		start := time.Now()
+		next(w, r, req.Method)
+		observeRequest(sanitizeMethodLabel(req.Method), time.Since(start).Seconds())
+	}
+}
+
+// handleJSONRPC answers every JSON-RPC v2 request with 501 Not Implemented
+// and an empty body; it does not dispatch to any method, including those
+// listed in knownMethods.
+func (a *APIServer) handleJSONRPC(w http.ResponseWriter, r *http.Request, method string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotImplemented)
+}